@@ -0,0 +1,26 @@
+package database
+
+import "time"
+
+// Backend abstracts the storage location that database files are written
+// to. LocalFileBackend is the original, filesystem-based implementation;
+// S3Backend and GCSBackend let a Backend push MMDB files straight to object
+// storage instead of (or in addition to) the local disk.
+type Backend interface {
+	// NewWriter returns a Writer for the named database. lastModified, if
+	// non-nil, is used the same way UpdateFilepath uses it today: backends
+	// that version their objects by timestamp fold it into the object name.
+	NewWriter(name string, lastModified *time.Time) (Writer, error)
+
+	// Exists reports whether a database with the given name is already
+	// present in the backend and, if so, returns its content hash so the
+	// caller can decide whether a fresh download is even necessary.
+	Exists(name string) (bool, string, error)
+
+	// Commit makes a Writer's contents visible under its final name. For
+	// object storage backends this is typically a copy from a temporary
+	// upload key to the published key, followed by deleting the temporary
+	// key; for the local filesystem it is the existing rename+symlink
+	// dance.
+	Commit(w Writer) error
+}
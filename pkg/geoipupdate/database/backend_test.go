@@ -0,0 +1,77 @@
+package database
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryBackendWriteAndCommit(t *testing.T) {
+	backend := NewMemoryBackend()
+
+	exists, _, err := backend.Exists("GeoIP2-City.mmdb")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	writer, err := backend.NewWriter("GeoIP2-City.mmdb", nil)
+	require.NoError(t, err)
+	assert.Equal(t, ZeroMD5, writer.GetHash())
+
+	_, err = writer.Write([]byte("a fake mmdb"))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	require.NoError(t, backend.Commit(writer))
+
+	exists, hash, err := backend.Exists("GeoIP2-City.mmdb")
+	require.NoError(t, err)
+	assert.True(t, exists)
+	assert.NotEmpty(t, hash)
+}
+
+func TestMemoryBackendValidHash(t *testing.T) {
+	backend := NewMemoryBackend()
+
+	writer, err := backend.NewWriter("GeoIP2-Country.mmdb", nil)
+	require.NoError(t, err)
+
+	_, err = writer.Write([]byte("content"))
+	require.NoError(t, err)
+
+	// md5sum-style known-good MD5 of "content".
+	require.NoError(t, writer.ValidHash("9a0364b9e99bb480dd25e1f0284c8555"))
+	assert.Error(t, writer.ValidHash("0000000000000000000000000000000"))
+}
+
+func TestMemoryBackendUpdateFilepathByTimestamp(t *testing.T) {
+	backend := NewMemoryBackend()
+	lastModified := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	writer, err := backend.NewWriter("GeoIP2-City.mmdb", &lastModified)
+	require.NoError(t, err)
+	_, err = writer.Write([]byte("snapshot"))
+	require.NoError(t, err)
+	require.NoError(t, backend.Commit(writer))
+
+	exists, _, err := backend.Exists("GeoIP2-City.mmdb")
+	require.NoError(t, err)
+	assert.False(t, exists, "committing with a lastModified time should not land on the bare name")
+
+	exists, _, err = backend.Exists("GeoIP2-City.mmdb-20200102T030405Z")
+	require.NoError(t, err)
+	assert.True(t, exists)
+}
+
+func TestMemoryBackendCommitRejectsForeignWriter(t *testing.T) {
+	backend := NewMemoryBackend()
+	other := NewMemoryBackend()
+
+	writer, err := other.NewWriter("GeoIP2-City.mmdb", nil)
+	require.NoError(t, err)
+
+	err = backend.Commit(writer)
+	require.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "different MemoryBackend"))
+}
@@ -0,0 +1,152 @@
+package database
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/blake2b"
+)
+
+// HashAlgorithm is a checksum algorithm that LocalFileDatabaseWriter can
+// record alongside the MaxMind-mandated MD5 that ValidHash checks. MD5 is
+// always computed, since the download API only ever advertises an MD5, but
+// callers who want stronger local integrity guarantees can ask for a
+// second hash to be written to a sidecar file.
+type HashAlgorithm string
+
+const (
+	// HashAlgorithmMD5 means no additional hash is computed; MD5 alone
+	// (already used for the API's ValidHash check) is considered enough.
+	HashAlgorithmMD5 HashAlgorithm = "md5"
+	// HashAlgorithmSHA256 writes a SHA-256 sidecar alongside the database.
+	HashAlgorithmSHA256 HashAlgorithm = "sha256"
+	// HashAlgorithmBlake2b256 writes a BLAKE2b-256 sidecar alongside the
+	// database.
+	HashAlgorithmBlake2b256 HashAlgorithm = "blake2b-256"
+)
+
+func (a HashAlgorithm) sidecarExtension() string {
+	switch a {
+	case HashAlgorithmSHA256:
+		return ".sha256"
+	case HashAlgorithmBlake2b256:
+		return ".blake2b"
+	default:
+		return ""
+	}
+}
+
+func newHash(algo HashAlgorithm) (hash.Hash, error) {
+	switch algo {
+	case HashAlgorithmSHA256:
+		return sha256.New(), nil
+	case HashAlgorithmBlake2b256:
+		return blake2b.New256(nil)
+	default:
+		return nil, errors.Errorf("unsupported strong hash algorithm %q", algo)
+	}
+}
+
+// hashSidecarWriter accumulates a strong hash of the bytes written to a
+// database and, once the database is committed, writes the resulting
+// digest to a small sidecar file beside it using the same
+// temp-file-then-rename dance as the database itself. It is structured
+// after nncp's TmpFileWHash: writes tee into a hash.Hash, and the
+// temporary file is only written and renamed once hashing is done.
+type hashSidecarWriter struct {
+	hash     hash.Hash
+	tempFile *os.File
+}
+
+func newHashSidecarWriter(tempPath string, algo HashAlgorithm) (*hashSidecarWriter, error) {
+	h, err := newHash(algo)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(tempPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644) //nolint:gosec
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating sidecar hash temporary file")
+	}
+	return &hashSidecarWriter{hash: h, tempFile: f}, nil
+}
+
+// Write feeds p into the strong hash. It does not touch the temporary
+// file; that is only written once, with the final digest, in commit.
+func (w *hashSidecarWriter) Write(p []byte) (int, error) {
+	return w.hash.Write(p)
+}
+
+// Sum returns the hex-encoded digest of everything written so far.
+func (w *hashSidecarWriter) Sum() string {
+	return fmt.Sprintf("%x", w.hash.Sum(nil))
+}
+
+// commit writes the final digest into the sidecar's temporary file, syncs
+// it (unless sync is false, per the writer's Durability setting), closes
+// it, and renames it to targetPath.
+func (w *hashSidecarWriter) commit(targetPath string, sync bool) error {
+	if _, err := w.tempFile.WriteString(w.Sum()); err != nil {
+		return errors.Wrap(err, "error writing sidecar hash file")
+	}
+	if sync {
+		if err := w.tempFile.Sync(); err != nil {
+			return errors.Wrap(err, "error syncing sidecar hash file")
+		}
+	}
+	if err := w.tempFile.Close(); err != nil {
+		return errors.Wrap(err, "error closing sidecar hash file")
+	}
+	if err := os.Rename(w.tempFile.Name(), targetPath); err != nil {
+		return errors.Wrap(err, "error moving sidecar hash file into place")
+	}
+	return nil
+}
+
+// discard removes the sidecar's temporary file without publishing it.
+func (w *hashSidecarWriter) discard() error {
+	if err := w.tempFile.Close(); err != nil && errors.Cause(err) != os.ErrClosed {
+		return errors.Wrap(err, "error closing sidecar hash temporary file")
+	}
+	if err := os.Remove(w.tempFile.Name()); err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "error removing sidecar hash temporary file")
+	}
+	return nil
+}
+
+// VerifyStrongHash recomputes algo's hash of the database at dbPath and
+// compares it against the digest recorded in its sidecar file. It is the
+// basis of --verify-strong-hash: a caller should refuse to serve dbPath if
+// this returns false or an error.
+func VerifyStrongHash(dbPath string, algo HashAlgorithm) (bool, error) {
+	ext := algo.sidecarExtension()
+	if ext == "" {
+		return false, errors.Errorf("unsupported strong hash algorithm %q", algo)
+	}
+
+	expected, err := os.ReadFile(dbPath + ext) //nolint:gosec
+	if err != nil {
+		return false, errors.Wrap(err, "error reading sidecar hash file")
+	}
+
+	h, err := newHash(algo)
+	if err != nil {
+		return false, err
+	}
+	f, err := os.Open(dbPath) //nolint:gosec
+	if err != nil {
+		return false, errors.Wrap(err, "error opening database")
+	}
+	defer f.Close() //nolint:errcheck
+
+	if _, err := io.Copy(h, f); err != nil {
+		return false, errors.Wrap(err, "error hashing database")
+	}
+
+	actual := fmt.Sprintf("%x", h.Sum(nil))
+	return strings.EqualFold(actual, strings.TrimSpace(string(expected))), nil
+}
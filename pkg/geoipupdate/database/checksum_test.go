@@ -0,0 +1,61 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalFileDatabaseWriterStrongHash(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "GeoIP2-City.mmdb")
+	lockPath := filepath.Join(dir, "GeoIP2-City.mmdb.lock")
+
+	writer, err := NewLocalFileDatabaseWriter(dbPath, lockPath, false, WithHashAlgorithm(HashAlgorithmSHA256))
+	require.NoError(t, err)
+
+	_, err = writer.Write([]byte("a fake mmdb"))
+	require.NoError(t, err)
+	assert.NotEmpty(t, writer.GetStrongHash())
+
+	require.NoError(t, writer.Commit())
+
+	sidecar, err := os.ReadFile(dbPath + ".sha256")
+	require.NoError(t, err)
+	assert.Equal(t, writer.GetStrongHash(), string(sidecar))
+
+	ok, err := VerifyStrongHash(dbPath, HashAlgorithmSHA256)
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestVerifyStrongHashDetectsCorruption(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "GeoIP2-City.mmdb")
+	lockPath := filepath.Join(dir, "GeoIP2-City.mmdb.lock")
+
+	writer, err := NewLocalFileDatabaseWriter(dbPath, lockPath, false, WithHashAlgorithm(HashAlgorithmBlake2b256))
+	require.NoError(t, err)
+	_, err = writer.Write([]byte("original contents"))
+	require.NoError(t, err)
+	require.NoError(t, writer.Commit())
+
+	require.NoError(t, os.WriteFile(dbPath, []byte("corrupted contents"), 0o644))
+
+	ok, err := VerifyStrongHash(dbPath, HashAlgorithmBlake2b256)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestLocalFileDatabaseWriterNoStrongHashByDefault(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "GeoIP2-City.mmdb")
+	lockPath := filepath.Join(dir, "GeoIP2-City.mmdb.lock")
+
+	writer, err := NewLocalFileDatabaseWriter(dbPath, lockPath, false)
+	require.NoError(t, err)
+	assert.Empty(t, writer.GetStrongHash())
+}
@@ -0,0 +1,75 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommitSkipsUnchangedContent(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "GeoIP2-City.mmdb")
+	lockPath := filepath.Join(dir, "GeoIP2-City.mmdb.lock")
+	require.NoError(t, os.WriteFile(dbPath, []byte("existing database"), 0o644))
+
+	writer, err := NewLocalFileDatabaseWriter(dbPath, lockPath, false)
+	require.NoError(t, err)
+	_, err = writer.Write([]byte("existing database"))
+	require.NoError(t, err)
+
+	require.NoError(t, writer.Commit())
+
+	assert.True(t, writer.skippedCommit)
+	assert.NoFileExists(t, writer.temporaryFile.Name())
+
+	contents, err := os.ReadFile(dbPath)
+	require.NoError(t, err)
+	assert.Equal(t, "existing database", string(contents))
+}
+
+func TestCommitAppliesChangedContent(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "GeoIP2-City.mmdb")
+	lockPath := filepath.Join(dir, "GeoIP2-City.mmdb.lock")
+	require.NoError(t, os.WriteFile(dbPath, []byte("old database"), 0o644))
+
+	writer, err := NewLocalFileDatabaseWriter(dbPath, lockPath, false)
+	require.NoError(t, err)
+	_, err = writer.Write([]byte("new database"))
+	require.NoError(t, err)
+
+	require.NoError(t, writer.Commit())
+
+	assert.False(t, writer.skippedCommit)
+	contents, err := os.ReadFile(dbPath)
+	require.NoError(t, err)
+	assert.Equal(t, "new database", string(contents))
+}
+
+func TestCommitUnchangedContentStillUpdatesAdvancedModTime(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "GeoIP2-City.mmdb")
+	lockPath := filepath.Join(dir, "GeoIP2-City.mmdb.lock")
+	require.NoError(t, os.WriteFile(dbPath, []byte("existing database"), 0o644))
+
+	oldModTime := time.Now().Add(-48 * time.Hour).Truncate(time.Second)
+	require.NoError(t, os.Chtimes(dbPath, oldModTime, oldModTime))
+
+	writer, err := NewLocalFileDatabaseWriter(dbPath, lockPath, false)
+	require.NoError(t, err)
+	_, err = writer.Write([]byte("existing database"))
+	require.NoError(t, err)
+	require.NoError(t, writer.Commit())
+	require.True(t, writer.skippedCommit)
+
+	newModTime := time.Now().Truncate(time.Second)
+	require.NoError(t, writer.SetFileModificationTime(newModTime))
+
+	info, err := os.Stat(dbPath)
+	require.NoError(t, err)
+	assert.True(t, info.ModTime().Equal(newModTime))
+}
@@ -0,0 +1,35 @@
+package database
+
+import "os"
+
+// Durability controls how hard LocalFileDatabaseWriter tries to make sure a
+// committed database survives a crash, trading safety for write speed. The
+// default, DurabilityFull, matches the writer's original behavior.
+type Durability string
+
+const (
+	// DurabilityFull fsyncs both the temporary file and the database
+	// directory before and after the rename, as the writer has always
+	// done. Use this on any filesystem where fsync is meaningful.
+	DurabilityFull Durability = "full"
+	// DurabilityDataOnly fsyncs the temporary file but skips the
+	// directory fsync.
+	DurabilityDataOnly Durability = "data-only"
+	// DurabilityNone skips both fsyncs entirely. This is appropriate for
+	// tmpfs, many network filesystems, and ephemeral containers, where
+	// fsync is either a no-op or needlessly expensive.
+	DurabilityNone Durability = "none"
+)
+
+// noSyncEnvVar, when set to "1", forces DurabilityNone regardless of any
+// Durability configured via WithDurability. It mirrors the GeoIP.conf
+// `Durability none` setting for callers that would rather control this
+// from the environment, e.g. in a container entrypoint.
+const noSyncEnvVar = "GEOIPUPDATE_NO_SYNC"
+
+func durabilityFromEnv(configured Durability) Durability {
+	if os.Getenv(noSyncEnvVar) == "1" {
+		return DurabilityNone
+	}
+	return configured
+}
@@ -0,0 +1,54 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDurabilityNoneStillCommits(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "GeoIP2-City.mmdb")
+	lockPath := filepath.Join(dir, "GeoIP2-City.mmdb.lock")
+
+	writer, err := NewLocalFileDatabaseWriter(dbPath, lockPath, false, WithDurability(DurabilityNone))
+	require.NoError(t, err)
+	_, err = writer.Write([]byte("a fake mmdb"))
+	require.NoError(t, err)
+	require.NoError(t, writer.Commit())
+
+	contents, err := os.ReadFile(dbPath)
+	require.NoError(t, err)
+	assert.Equal(t, "a fake mmdb", string(contents))
+}
+
+func TestGeoipupdateNoSyncEnvVarOverridesOption(t *testing.T) {
+	t.Setenv(noSyncEnvVar, "1")
+
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "GeoIP2-City.mmdb")
+	lockPath := filepath.Join(dir, "GeoIP2-City.mmdb.lock")
+
+	writer, err := NewLocalFileDatabaseWriter(dbPath, lockPath, false, WithDurability(DurabilityFull))
+	require.NoError(t, err)
+	assert.Equal(t, DurabilityNone, writer.durability)
+}
+
+func TestDurabilityDataOnlyStillCommits(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "GeoIP2-City.mmdb")
+	lockPath := filepath.Join(dir, "GeoIP2-City.mmdb.lock")
+
+	writer, err := NewLocalFileDatabaseWriter(dbPath, lockPath, false, WithDurability(DurabilityDataOnly))
+	require.NoError(t, err)
+	_, err = writer.Write([]byte("a fake mmdb"))
+	require.NoError(t, err)
+	require.NoError(t, writer.Commit())
+
+	contents, err := os.ReadFile(dbPath)
+	require.NoError(t, err)
+	assert.Equal(t, "a fake mmdb", string(contents))
+}
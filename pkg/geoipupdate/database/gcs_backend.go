@@ -0,0 +1,157 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5" //nolint:gosec
+	"fmt"
+	"hash"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/pkg/errors"
+)
+
+// GCSBackend is a Backend that stores databases as objects in a Google
+// Cloud Storage bucket, using the same upload-to-temp-then-copy pattern as
+// S3Backend so a reader never sees a partially written database.
+type GCSBackend struct {
+	bucket *storage.BucketHandle
+	prefix string
+}
+
+// NewGCSBackend returns a GCSBackend that stores objects under prefix in
+// the named bucket, using client for all operations.
+func NewGCSBackend(client *storage.Client, bucketName, prefix string) *GCSBackend {
+	return &GCSBackend{
+		bucket: client.Bucket(bucketName),
+		prefix: prefix,
+	}
+}
+
+func (b *GCSBackend) objectName(name string) string {
+	return strings.TrimSuffix(b.prefix, "/") + "/" + name
+}
+
+func (b *GCSBackend) tempObjectName(name string) string {
+	return b.objectName(".tmp-" + name)
+}
+
+// NewWriter returns a Writer that buffers the database locally; the upload
+// happens in Commit so that it can be verified before becoming visible.
+func (b *GCSBackend) NewWriter(name string, lastModified *time.Time) (Writer, error) {
+	if lastModified != nil {
+		name = fmt.Sprintf("%s-%s", name, lastModified.UTC().Format(timestampFormat))
+	}
+
+	oldHash := ZeroMD5
+	if exists, hash, err := b.Exists(name); err != nil {
+		return nil, err
+	} else if exists {
+		oldHash = hash
+	}
+
+	return &gcsWriter{
+		backend: b,
+		name:    name,
+		oldHash: oldHash,
+		buf:     &bytes.Buffer{},
+		md5Hash: md5.New(), //nolint:gosec
+	}, nil
+}
+
+// Exists reports whether name is already present in the bucket and, if so,
+// returns its MD5 hash.
+func (b *GCSBackend) Exists(name string) (bool, string, error) {
+	attrs, err := b.bucket.Object(b.objectName(name)).Attrs(context.Background())
+	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return false, "", nil
+		}
+		return false, "", errors.Wrap(err, "error checking whether database exists in GCS")
+	}
+	return true, fmt.Sprintf("%x", attrs.MD5), nil
+}
+
+// Commit uploads the buffered database to a temporary object, verifies its
+// MD5, copies it onto the final object name, and deletes the temporary
+// object.
+func (b *GCSBackend) Commit(w Writer) error {
+	gw, ok := w.(*gcsWriter)
+	if !ok {
+		return errors.Errorf("GCSBackend.Commit called with a Writer of type %T", w)
+	}
+	if gw.backend != b {
+		return errors.Errorf("GCSBackend.Commit called with a Writer from a different GCSBackend")
+	}
+
+	ctx := context.Background()
+	tempObj := b.bucket.Object(b.tempObjectName(gw.name))
+
+	writer := tempObj.NewWriter(ctx)
+	if _, err := writer.Write(gw.buf.Bytes()); err != nil {
+		_ = writer.Close()
+		return errors.Wrap(err, "error uploading database to GCS")
+	}
+	if err := writer.Close(); err != nil {
+		return errors.Wrap(err, "error finalizing database upload to GCS")
+	}
+
+	expectedMD5 := gw.md5Hash.Sum(nil)
+	if !bytes.Equal(writer.Attrs().MD5, expectedMD5) {
+		return errors.Errorf(
+			"GCS upload MD5 (%x) does not match local MD5 (%x)", writer.Attrs().MD5, expectedMD5,
+		)
+	}
+
+	finalObj := b.bucket.Object(b.objectName(gw.name))
+	if _, err := finalObj.CopierFrom(tempObj).Run(ctx); err != nil {
+		return errors.Wrap(err, "error promoting uploaded database to its final GCS object")
+	}
+	if err := tempObj.Delete(ctx); err != nil {
+		return errors.Wrap(err, "error removing temporary GCS object")
+	}
+	return nil
+}
+
+type gcsWriter struct {
+	backend      *GCSBackend
+	name         string
+	oldHash      string
+	buf          *bytes.Buffer
+	md5Hash      hash.Hash
+	lastModified time.Time
+}
+
+func (w *gcsWriter) Write(p []byte) (int, error) {
+	w.md5Hash.Write(p) //nolint:errcheck
+	return w.buf.Write(p)
+}
+
+func (w *gcsWriter) Close() error {
+	return nil
+}
+
+func (w *gcsWriter) ValidHash(expectedHash string) error {
+	actualHash := fmt.Sprintf("%x", w.md5Hash.Sum(nil))
+	if !strings.EqualFold(actualHash, expectedHash) {
+		return errors.Errorf("md5 of new database (%s) does not match expected md5 (%s)", actualHash, expectedHash)
+	}
+	return nil
+}
+
+func (w *gcsWriter) UpdateFilepath(lastModified *time.Time) {
+	if lastModified != nil {
+		w.name = fmt.Sprintf("%s-%s", w.name, lastModified.UTC().Format(timestampFormat))
+	}
+}
+
+func (w *gcsWriter) SetFileModificationTime(lastModified time.Time) error {
+	w.lastModified = lastModified
+	return nil
+}
+
+func (w *gcsWriter) GetHash() string {
+	return w.oldHash
+}
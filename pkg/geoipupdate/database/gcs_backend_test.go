@@ -0,0 +1,63 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/fsouza/fake-gcs-server/fakestorage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestGCSBackend spins up an in-process fake GCS server (fake-gcs-server)
+// so the upload-to-temp-object-then-copy path can be exercised without real
+// GCP credentials or network access.
+func newTestGCSBackend(t *testing.T) (*GCSBackend, func()) {
+	t.Helper()
+
+	const bucket = "geoip-test"
+	server := fakestorage.NewServer([]fakestorage.Object{})
+	server.CreateBucketWithOpts(fakestorage.CreateBucketOpts{Name: bucket})
+
+	backend := NewGCSBackend(server.Client(), bucket, "databases")
+	return backend, server.Stop
+}
+
+func TestGCSBackendWriteAndCommit(t *testing.T) {
+	backend, cleanup := newTestGCSBackend(t)
+	defer cleanup()
+
+	exists, _, err := backend.Exists("GeoIP2-City.mmdb")
+	require.NoError(t, err)
+	require.False(t, exists)
+
+	writer, err := backend.NewWriter("GeoIP2-City.mmdb", nil)
+	require.NoError(t, err)
+
+	_, err = writer.Write([]byte("a fake mmdb"))
+	require.NoError(t, err)
+	require.NoError(t, backend.Commit(writer))
+
+	exists, hash, err := backend.Exists("GeoIP2-City.mmdb")
+	require.NoError(t, err)
+	require.True(t, exists)
+	require.NotEmpty(t, hash)
+
+	// The temporary upload object should not be left behind.
+	tempExists, _, err := backend.Exists(".tmp-GeoIP2-City.mmdb")
+	require.NoError(t, err)
+	require.False(t, tempExists)
+}
+
+func TestGCSBackendCommitRejectsForeignWriter(t *testing.T) {
+	backend, cleanup := newTestGCSBackend(t)
+	defer cleanup()
+	other, otherCleanup := newTestGCSBackend(t)
+	defer otherCleanup()
+
+	writer, err := other.NewWriter("GeoIP2-City.mmdb", nil)
+	require.NoError(t, err)
+
+	err = backend.Commit(writer)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "different GCSBackend")
+}
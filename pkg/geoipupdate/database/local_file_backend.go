@@ -0,0 +1,69 @@
+package database
+
+import (
+	"crypto/md5" //nolint:gosec
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// LocalFileBackend is the Backend implementation that writes databases to
+// the local filesystem via LocalFileDatabaseWriter. It is the default
+// backend and preserves the locking and atomic-rename behavior the package
+// has always had.
+type LocalFileBackend struct {
+	lockFilePath string
+	verbose      bool
+}
+
+// NewLocalFileBackend creates a Backend that stores each database at the
+// path passed to NewWriter, using lockFilePath to serialize concurrent
+// writers.
+func NewLocalFileBackend(lockFilePath string, verbose bool) *LocalFileBackend {
+	return &LocalFileBackend{
+		lockFilePath: lockFilePath,
+		verbose:      verbose,
+	}
+}
+
+// NewWriter returns a LocalFileDatabaseWriter for the database at name.
+func (b *LocalFileBackend) NewWriter(name string, lastModified *time.Time) (Writer, error) {
+	writer, err := NewLocalFileDatabaseWriter(name, b.lockFilePath, b.verbose)
+	if err != nil {
+		return nil, err
+	}
+	writer.UpdateFilepath(lastModified)
+	return writer, nil
+}
+
+// Exists reports whether name is already present on disk and, if so,
+// returns its MD5 hash.
+func (b *LocalFileBackend) Exists(name string) (bool, string, error) {
+	f, err := os.Open(name) //nolint:gosec
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, "", nil
+		}
+		return false, "", errors.Wrap(err, "error opening database")
+	}
+	defer f.Close() //nolint:errcheck
+
+	hash := md5.New() //nolint:gosec
+	if _, err := io.Copy(hash, f); err != nil {
+		return false, "", errors.Wrap(err, "error calculating database hash")
+	}
+	return true, fmt.Sprintf("%x", hash.Sum(nil)), nil
+}
+
+// Commit finalizes a Writer previously returned by NewWriter. w must be a
+// *LocalFileDatabaseWriter returned by this backend.
+func (b *LocalFileBackend) Commit(w Writer) error {
+	localWriter, ok := w.(*LocalFileDatabaseWriter)
+	if !ok {
+		return errors.Errorf("LocalFileBackend.Commit called with a Writer of type %T", w)
+	}
+	return localWriter.Commit()
+}
@@ -8,6 +8,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -29,18 +30,41 @@ type LocalFileDatabaseWriter struct {
 	temporaryFile *os.File
 	md5Writer     hash.Hash
 	lastModified  time.Time
+
+	retainMode     RetainMode
+	retainVersions int
+	retainAge      time.Duration
+
+	hashAlgorithm HashAlgorithm
+	sidecarWriter *hashSidecarWriter
+
+	skippedCommit bool
+
+	durability Durability
+
+	validator Validator
+	editionID string
 }
 
 // NewLocalFileDatabaseWriter create a LocalFileDatabaseWriter. It creates the
 // necessary lock and temporary files to protect the database from concurrent
-// writes.
-func NewLocalFileDatabaseWriter(filePath string, lockFilePath string, verbose bool) (*LocalFileDatabaseWriter, error) {
+// writes. opts may be used to enable optional behavior such as retention of
+// old timestamped snapshots (see WithRetention) or a stronger local
+// checksum (see WithHashAlgorithm).
+func NewLocalFileDatabaseWriter(filePath string, lockFilePath string, verbose bool, opts ...WriterOption) (*LocalFileDatabaseWriter, error) {
 	dbWriter := &LocalFileDatabaseWriter{
 		filePath:     filePath,
 		symlinkPath:  filePath,
 		lockFilePath: lockFilePath,
 		verbose:      verbose,
+		retainMode:   RetainModeNone,
+		durability:   DurabilityFull,
+		validator:    NoopValidator{},
 	}
+	for _, opt := range opts {
+		opt(dbWriter)
+	}
+	dbWriter.durability = durabilityFromEnv(dbWriter.durability)
 
 	var err error
 	if dbWriter.lock, err = CreateLockFile(lockFilePath, verbose); err != nil {
@@ -60,7 +84,18 @@ func NewLocalFileDatabaseWriter(filePath string, lockFilePath string, verbose bo
 		return nil, errors.Wrap(err, "error creating temporary file")
 	}
 	dbWriter.md5Writer = md5.New()
-	dbWriter.fileWriter = io.MultiWriter(dbWriter.md5Writer, dbWriter.temporaryFile)
+	writers := []io.Writer{dbWriter.md5Writer, dbWriter.temporaryFile}
+
+	if dbWriter.hashAlgorithm != "" && dbWriter.hashAlgorithm != HashAlgorithmMD5 {
+		sidecarTemp := fmt.Sprintf("%s.temporary%s", dbWriter.filePath, dbWriter.hashAlgorithm.sidecarExtension())
+		dbWriter.sidecarWriter, err = newHashSidecarWriter(sidecarTemp, dbWriter.hashAlgorithm)
+		if err != nil {
+			return nil, err
+		}
+		writers = append(writers, dbWriter.sidecarWriter)
+	}
+
+	dbWriter.fileWriter = io.MultiWriter(writers...)
 
 	return dbWriter, nil
 }
@@ -105,12 +140,27 @@ func (writer *LocalFileDatabaseWriter) Close() error {
 	if err := os.Remove(writer.temporaryFile.Name()); err != nil && errors.Cause(err) == os.ErrNotExist {
 		return errors.Wrap(err, "error removing temporary file")
 	}
+	if writer.sidecarWriter != nil {
+		if err := writer.sidecarWriter.discard(); err != nil {
+			return err
+		}
+	}
 	if err := writer.lock.Unlock(); err != nil {
 		return errors.Wrap(err, "error releasing lock file")
 	}
 	return nil
 }
 
+// GetStrongHash returns the hex-encoded digest of the stronger hash
+// configured via WithHashAlgorithm, computed over the bytes written so
+// far. It returns the empty string if no HashAlgorithm was configured.
+func (writer *LocalFileDatabaseWriter) GetStrongHash() string {
+	if writer.sidecarWriter == nil {
+		return ""
+	}
+	return writer.sidecarWriter.Sum()
+}
+
 // ValidHash checks that the temporary file's MD5 matches the given hash.
 func (writer *LocalFileDatabaseWriter) ValidHash(expectedHash string) error {
 	actualHash := fmt.Sprintf("%x", writer.md5Writer.Sum(nil))
@@ -127,9 +177,20 @@ func (writer *LocalFileDatabaseWriter) UpdateFilepath(lastModified *time.Time) {
 }
 
 // SetFileModificationTime sets the database's file access and modified times
-// to the given time.
+// to the given time. If the file's current modification time already
+// matches, it leaves the file untouched.
 func (writer *LocalFileDatabaseWriter) SetFileModificationTime(lastModified time.Time) error {
-	if err := os.Chtimes(writer.filePath, lastModified, lastModified); err != nil {
+	target := writer.filePath
+	if writer.skippedCommit {
+		target = writer.symlinkPath
+	}
+
+	if info, err := os.Stat(target); err == nil && info.ModTime().Equal(lastModified) {
+		writer.lastModified = lastModified
+		return nil
+	}
+
+	if err := os.Chtimes(target, lastModified, lastModified); err != nil {
 		return errors.Wrap(err, "error setting times on file")
 	}
 	writer.lastModified = lastModified
@@ -137,10 +198,24 @@ func (writer *LocalFileDatabaseWriter) SetFileModificationTime(lastModified time
 }
 
 // Commit renames the temporary file to the name of the database file and syncs
-// the directory.
+// the directory. If the downloaded content is byte-identical to the
+// database already on disk, it discards the temporary file instead and
+// leaves the existing database, its symlink, and (for the timestamped
+// snapshot workflow) its directory listing untouched.
 func (writer *LocalFileDatabaseWriter) Commit() error {
-	if err := writer.temporaryFile.Sync(); err != nil {
-		return errors.Wrap(err, "error syncing temporary file")
+	if fmt.Sprintf("%x", writer.md5Writer.Sum(nil)) == writer.oldHash {
+		return writer.commitUnchanged()
+	}
+
+	snapshot, err := writer.snapshotForRollback()
+	if err != nil {
+		return err
+	}
+
+	if writer.durability != DurabilityNone {
+		if err := writer.temporaryFile.Sync(); err != nil {
+			return errors.Wrap(err, "error syncing temporary file")
+		}
 	}
 	if err := writer.temporaryFile.Close(); err != nil {
 		return errors.Wrap(err, "error closing temporary file")
@@ -153,6 +228,13 @@ func (writer *LocalFileDatabaseWriter) Commit() error {
 		return err
 	}
 
+	if writer.sidecarWriter != nil {
+		sidecarPath := writer.filePath + writer.hashAlgorithm.sidecarExtension()
+		if err := writer.sidecarWriter.commit(sidecarPath, writer.durability != DurabilityNone); err != nil {
+			return err
+		}
+	}
+
 	// Create a symlink to the recently downloaded file
 	// target file is expected to be in same directory as the symlink
 	if writer.symlinkPath != writer.filePath {
@@ -162,6 +244,122 @@ func (writer *LocalFileDatabaseWriter) Commit() error {
 		if err := os.Symlink(filepath.Base(writer.filePath), writer.symlinkPath); err != nil {
 			return errors.Wrap(err, "error creating symlink to new database file")
 		}
+		if err := writer.fsyncDir(); err != nil {
+			return err
+		}
+	}
+
+	// Validate before pruning old snapshots: if validation fails, rollback
+	// needs the previous snapshot (or sidecar) that pruning would
+	// otherwise have already deleted as no-longer-current.
+	if err := writer.validator.Validate(writer.filePath, writer.editionID); err != nil {
+		if rollbackErr := writer.rollback(snapshot); rollbackErr != nil {
+			return errors.Wrap(rollbackErr, err.Error())
+		}
+		return errors.Wrap(err, "new database failed post-commit validation; rolled back to previous version")
+	}
+
+	if err := writer.cleanupRollbackSnapshot(snapshot); err != nil {
+		return err
+	}
+
+	return writer.pruneOldSnapshots()
+}
+
+// commitUnchanged discards the downloaded temporary file (and sidecar hash
+// file, if any) without renaming anything into place, since their content
+// is identical to what is already on disk.
+func (writer *LocalFileDatabaseWriter) commitUnchanged() error {
+	if writer.verbose {
+		log.Printf("New database for %s is unchanged; skipping commit", writer.symlinkPath)
+	}
+
+	if err := writer.temporaryFile.Close(); err != nil && errors.Cause(err) != os.ErrClosed {
+		return errors.Wrap(err, "error closing temporary file")
+	}
+	if err := os.Remove(writer.temporaryFile.Name()); err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "error removing temporary file")
+	}
+	if writer.sidecarWriter != nil {
+		if err := writer.sidecarWriter.discard(); err != nil {
+			return err
+		}
+	}
+
+	writer.skippedCommit = true
+	return nil
+}
+
+// pruneOldSnapshots deletes old timestamped snapshot files according to the
+// writer's retention settings. It never deletes writer.filePath, the
+// snapshot that was just committed and that the symlink now points to, and
+// it only ever considers files whose name is an exact
+// "<symlink base>-<timestamp>" match, so other databases sharing the same
+// directory are left untouched.
+func (writer *LocalFileDatabaseWriter) pruneOldSnapshots() error {
+	if writer.retainMode == RetainModeNone || writer.retainMode == "" {
+		return nil
+	}
+
+	dir := filepath.Dir(writer.symlinkPath)
+	prefix := filepath.Base(writer.symlinkPath) + "-"
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return errors.Wrap(err, "error reading database directory")
+	}
+
+	type snapshot struct {
+		path      string
+		timestamp time.Time
+	}
+	var snapshots []snapshot
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		ts, err := time.Parse(timestampFormat, strings.TrimPrefix(name, prefix))
+		if err != nil {
+			// Not a full timestamp suffix (e.g. a sidecar file or a name
+			// that merely happens to share the prefix); leave it alone.
+			continue
+		}
+		snapshots = append(snapshots, snapshot{path: filepath.Join(dir, name), timestamp: ts})
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].timestamp.After(snapshots[j].timestamp)
+	})
+
+	currentTarget := filepath.Base(writer.filePath)
+	now := time.Now()
+	var removedAny bool
+	for i, snap := range snapshots {
+		if filepath.Base(snap.path) == currentTarget {
+			continue
+		}
+
+		keep := true
+		switch writer.retainMode {
+		case RetainModeCount:
+			keep = i < writer.retainVersions
+		case RetainModeAge:
+			keep = writer.retainAge <= 0 || now.Sub(snap.timestamp) <= writer.retainAge
+		case RetainModeBoth:
+			keep = i < writer.retainVersions && (writer.retainAge <= 0 || now.Sub(snap.timestamp) <= writer.retainAge)
+		}
+		if keep {
+			continue
+		}
+
+		if err := os.Remove(snap.path); err != nil && !os.IsNotExist(err) {
+			return errors.Wrapf(err, "error removing old database snapshot %s", snap.path)
+		}
+		removedAny = true
+	}
+
+	if removedAny {
 		return writer.fsyncDir()
 	}
 	return nil
@@ -169,13 +367,17 @@ func (writer *LocalFileDatabaseWriter) Commit() error {
 
 // fsync the directory. http://austingroupbugs.net/view.php?id=672
 func (writer *LocalFileDatabaseWriter) fsyncDir() error {
+	if writer.durability != DurabilityFull {
+		return nil
+	}
+
 	dh, err := os.Open(filepath.Dir(writer.filePath))
 	if err != nil {
 		return errors.Wrap(err, "error opening database directory")
 	}
 	defer func() {
 		if err := dh.Close(); err != nil {
-			log.Fatalf("Error closing directory: %+v", errors.Wrap(err, "closing directory"))
+			log.Println(errors.Wrap(err, "error closing database directory"))
 		}
 	}()
 
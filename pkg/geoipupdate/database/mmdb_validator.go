@@ -0,0 +1,60 @@
+package database
+
+import (
+	"net"
+	"strings"
+
+	"github.com/oschwald/maxminddb-golang"
+	"github.com/pkg/errors"
+)
+
+// MMDBValidator re-opens a committed database with maxminddb-golang,
+// confirms its metadata parses and DatabaseType matches the edition ID
+// that was requested, and performs a sample lookup to confirm the search
+// tree is actually traversable, not just that the file opens.
+type MMDBValidator struct{}
+
+// probePrefixes maps an edition ID prefix to an IP address known to
+// return a result in that kind of database, so Validate's sample lookup
+// exercises a real tree path instead of an arbitrary address.
+var probePrefixes = []struct {
+	prefix string
+	ip     string
+}{
+	{"GeoIP2-ASN", "8.8.8.8"},
+	{"GeoLite2-ASN", "8.8.8.8"},
+	{"GeoIP2", "1.1.1.1"},
+	{"GeoLite2", "1.1.1.1"},
+}
+
+// Validate implements Validator.
+func (MMDBValidator) Validate(path string, editionID string) error {
+	reader, err := maxminddb.Open(path)
+	if err != nil {
+		return errors.Wrap(err, "error opening database for validation")
+	}
+	defer reader.Close() //nolint:errcheck
+
+	if reader.Metadata.DatabaseType != editionID {
+		return errors.Errorf(
+			"database at %s has type %q, expected %q",
+			path,
+			reader.Metadata.DatabaseType,
+			editionID,
+		)
+	}
+
+	probe := "1.1.1.1"
+	for _, p := range probePrefixes {
+		if strings.HasPrefix(editionID, p.prefix) {
+			probe = p.ip
+			break
+		}
+	}
+
+	var result interface{}
+	if err := reader.Lookup(net.ParseIP(probe), &result); err != nil {
+		return errors.Wrap(err, "error performing sample lookup on database")
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package database
+
+import "time"
+
+// RetainMode selects how LocalFileDatabaseWriter prunes old timestamped
+// snapshots after a successful Commit.
+type RetainMode string
+
+const (
+	// RetainModeNone disables pruning; every timestamped snapshot is kept
+	// forever. This is the default and preserves existing behavior.
+	RetainModeNone RetainMode = "none"
+	// RetainModeCount keeps only the RetainVersions most recent snapshots.
+	RetainModeCount RetainMode = "count"
+	// RetainModeAge deletes snapshots older than RetainAge.
+	RetainModeAge RetainMode = "age"
+	// RetainModeBoth applies both the count and age limits, deleting a
+	// snapshot if either would remove it.
+	RetainModeBoth RetainMode = "both"
+)
+
+// WriterOption configures optional behavior of a LocalFileDatabaseWriter.
+// Options are applied in NewLocalFileDatabaseWriter, after the writer's
+// required fields are set.
+type WriterOption func(*LocalFileDatabaseWriter)
+
+// WithRetention configures pruning of old timestamped snapshot files
+// following GeoIP.conf's RetainMode, RetainVersions, and RetainAge
+// settings. It has no effect on databases that are not using timestamped
+// snapshots (i.e. UpdateFilepath is never called with a non-nil time).
+func WithRetention(mode RetainMode, versions int, age time.Duration) WriterOption {
+	return func(writer *LocalFileDatabaseWriter) {
+		writer.retainMode = mode
+		writer.retainVersions = versions
+		writer.retainAge = age
+	}
+}
+
+// WithHashAlgorithm makes the writer compute an additional, stronger hash
+// of the database as it is written and publish it to a sidecar file (see
+// HashAlgorithm). HashAlgorithmMD5, like the zero value, leaves the
+// writer's behavior unchanged: only the API-mandated MD5 is computed.
+func WithHashAlgorithm(algo HashAlgorithm) WriterOption {
+	return func(writer *LocalFileDatabaseWriter) {
+		writer.hashAlgorithm = algo
+	}
+}
+
+// WithDurability configures how aggressively the writer fsyncs when
+// committing a database; see Durability. The GEOIPUPDATE_NO_SYNC
+// environment variable always wins over this option when set to "1".
+func WithDurability(durability Durability) WriterOption {
+	return func(writer *LocalFileDatabaseWriter) {
+		writer.durability = durability
+	}
+}
+
+// WithValidator makes Commit validate the database with v once it has
+// been written, rolling the commit back on failure. editionID is passed
+// to v.Validate unchanged; it should be the edition ID (e.g.
+// "GeoIP2-City") that was requested for this database. The default is
+// NoopValidator, which performs no validation.
+func WithValidator(v Validator, editionID string) WriterOption {
+	return func(writer *LocalFileDatabaseWriter) {
+		writer.validator = v
+		writer.editionID = editionID
+	}
+}
@@ -0,0 +1,111 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeSnapshot(t *testing.T, dir, base string, ts time.Time) string {
+	t.Helper()
+	name := filepath.Join(dir, base+"-"+ts.UTC().Format(timestampFormat))
+	require.NoError(t, os.WriteFile(name, []byte("snapshot"), 0o644))
+	return name
+}
+
+func commitWithRetention(t *testing.T, symlinkPath, currentFilePath string, opt WriterOption) {
+	t.Helper()
+	writer := &LocalFileDatabaseWriter{
+		filePath:    currentFilePath,
+		symlinkPath: symlinkPath,
+	}
+	opt(writer)
+	require.NoError(t, writer.pruneOldSnapshots())
+}
+
+func TestPruneOldSnapshotsByCount(t *testing.T) {
+	dir := t.TempDir()
+	symlinkPath := filepath.Join(dir, "GeoIP2-City.mmdb")
+
+	base := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	var paths []string
+	for i := 0; i < 5; i++ {
+		paths = append(paths, writeSnapshot(t, dir, "GeoIP2-City.mmdb", base.AddDate(0, 0, i)))
+	}
+	current := paths[len(paths)-1]
+
+	commitWithRetention(t, symlinkPath, current, WithRetention(RetainModeCount, 2, 0))
+
+	for i, p := range paths {
+		_, err := os.Stat(p)
+		if i >= len(paths)-2 {
+			assert.NoError(t, err, "expected recent snapshot %s to survive", p)
+		} else {
+			assert.True(t, os.IsNotExist(err), "expected old snapshot %s to be pruned", p)
+		}
+	}
+}
+
+func TestPruneOldSnapshotsNeverDeletesCurrent(t *testing.T) {
+	dir := t.TempDir()
+	symlinkPath := filepath.Join(dir, "GeoIP2-City.mmdb")
+
+	base := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	current := writeSnapshot(t, dir, "GeoIP2-City.mmdb", base)
+
+	commitWithRetention(t, symlinkPath, current, WithRetention(RetainModeCount, 0, 0))
+
+	_, err := os.Stat(current)
+	assert.NoError(t, err, "the snapshot the symlink now points to must never be pruned")
+}
+
+func TestPruneOldSnapshotsIgnoresPartialMatches(t *testing.T) {
+	dir := t.TempDir()
+	symlinkPath := filepath.Join(dir, "GeoIP2-City.mmdb")
+
+	base := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	current := writeSnapshot(t, dir, "GeoIP2-City.mmdb", base)
+
+	// Looks like a snapshot but does not have a full, parseable timestamp
+	// suffix; pruning must leave it alone rather than erroring out.
+	partial := filepath.Join(dir, "GeoIP2-City.mmdb-not-a-timestamp")
+	require.NoError(t, os.WriteFile(partial, []byte("x"), 0o644))
+
+	commitWithRetention(t, symlinkPath, current, WithRetention(RetainModeCount, 0, 0))
+
+	_, err := os.Stat(partial)
+	assert.NoError(t, err, "files without a full timestamp suffix must not be touched")
+}
+
+func TestPruneOldSnapshotsCrossDBIsolation(t *testing.T) {
+	dir := t.TempDir()
+	symlinkPath := filepath.Join(dir, "GeoIP2-City.mmdb")
+
+	base := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	current := writeSnapshot(t, dir, "GeoIP2-City.mmdb", base)
+	other := writeSnapshot(t, dir, "GeoIP2-Country.mmdb", base.AddDate(0, 0, -30))
+
+	commitWithRetention(t, symlinkPath, current, WithRetention(RetainModeCount, 0, 0))
+
+	_, err := os.Stat(other)
+	assert.NoError(t, err, "pruning GeoIP2-City.mmdb must not remove GeoIP2-Country.mmdb's snapshots")
+}
+
+func TestPruneOldSnapshotsByAge(t *testing.T) {
+	dir := t.TempDir()
+	symlinkPath := filepath.Join(dir, "GeoIP2-City.mmdb")
+
+	old := writeSnapshot(t, dir, "GeoIP2-City.mmdb", time.Now().AddDate(0, 0, -10))
+	current := writeSnapshot(t, dir, "GeoIP2-City.mmdb", time.Now())
+
+	commitWithRetention(t, symlinkPath, current, WithRetention(RetainModeAge, 0, 24*time.Hour))
+
+	_, err := os.Stat(old)
+	assert.True(t, os.IsNotExist(err), "snapshot older than RetainAge should be pruned")
+	_, err = os.Stat(current)
+	assert.NoError(t, err)
+}
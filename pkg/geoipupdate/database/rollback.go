@@ -0,0 +1,152 @@
+package database
+
+import (
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// commitSnapshot captures what Commit needs to undo a database write if
+// post-commit validation (see Validator) fails. It is taken before any
+// file is renamed or symlinked into place.
+type commitSnapshot struct {
+	// backupPath, if non-empty, is a copy of the file that used to live at
+	// writer.filePath before Commit overwrote it in place. Only set when
+	// the writer isn't using timestamped snapshots (symlinkPath ==
+	// filePath), since in that mode Commit overwrites the one file a
+	// reader actually uses.
+	backupPath string
+
+	// sidecarBackupPath, if non-empty, is a copy of the sidecar hash file
+	// (see WithHashAlgorithm) that used to live beside writer.filePath
+	// before Commit overwrote it in place. Like backupPath, it is only
+	// set in the non-versioned case, since Commit's sidecar rename
+	// target is the same path on every run.
+	sidecarBackupPath string
+
+	// hadSymlink and symlinkTarget record the symlink's previous state, so
+	// a versioned commit (symlinkPath != filePath) can be undone by
+	// repointing the symlink rather than restoring file content.
+	hadSymlink    bool
+	symlinkTarget string
+}
+
+func (writer *LocalFileDatabaseWriter) snapshotForRollback() (*commitSnapshot, error) {
+	snapshot := &commitSnapshot{}
+
+	if writer.symlinkPath != writer.filePath {
+		target, err := os.Readlink(writer.symlinkPath)
+		switch {
+		case err == nil:
+			snapshot.hadSymlink = true
+			snapshot.symlinkTarget = target
+		case os.IsNotExist(err):
+			// No previous symlink; nothing to restore.
+		default:
+			return nil, errors.Wrap(err, "error reading existing database symlink")
+		}
+		return snapshot, nil
+	}
+
+	if _, err := os.Stat(writer.filePath); err != nil {
+		if os.IsNotExist(err) {
+			return snapshot, nil
+		}
+		return nil, errors.Wrap(err, "error checking for existing database")
+	}
+
+	backupPath := writer.filePath + ".rollback"
+	if err := copyFileContents(writer.filePath, backupPath); err != nil {
+		return nil, errors.Wrap(err, "error snapshotting existing database before commit")
+	}
+	snapshot.backupPath = backupPath
+
+	if writer.sidecarWriter != nil {
+		sidecarPath := writer.filePath + writer.hashAlgorithm.sidecarExtension()
+		if _, err := os.Stat(sidecarPath); err == nil {
+			sidecarBackupPath := sidecarPath + ".rollback"
+			if err := copyFileContents(sidecarPath, sidecarBackupPath); err != nil {
+				return nil, errors.Wrap(err, "error snapshotting existing sidecar hash file before commit")
+			}
+			snapshot.sidecarBackupPath = sidecarBackupPath
+		} else if !os.IsNotExist(err) {
+			return nil, errors.Wrap(err, "error checking for existing sidecar hash file")
+		}
+	}
+
+	return snapshot, nil
+}
+
+// rollback undoes a Commit whose database failed post-commit validation,
+// restoring whatever snapshotForRollback captured.
+func (writer *LocalFileDatabaseWriter) rollback(snapshot *commitSnapshot) error {
+	if err := os.Remove(writer.filePath); err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "error removing invalid database")
+	}
+	if writer.sidecarWriter != nil {
+		sidecarPath := writer.filePath + writer.hashAlgorithm.sidecarExtension()
+		if err := os.Remove(sidecarPath); err != nil && !os.IsNotExist(err) {
+			return errors.Wrap(err, "error removing invalid database's sidecar hash file")
+		}
+		if snapshot.sidecarBackupPath != "" {
+			if err := os.Rename(snapshot.sidecarBackupPath, sidecarPath); err != nil {
+				return errors.Wrap(err, "error restoring previous sidecar hash file from rollback snapshot")
+			}
+		}
+	}
+
+	if writer.symlinkPath != writer.filePath {
+		if err := os.Remove(writer.symlinkPath); err != nil && !os.IsNotExist(err) {
+			return errors.Wrap(err, "error removing symlink to invalid database")
+		}
+		if snapshot.hadSymlink {
+			if err := os.Symlink(snapshot.symlinkTarget, writer.symlinkPath); err != nil {
+				return errors.Wrap(err, "error restoring symlink to previous database")
+			}
+		}
+		return writer.fsyncDir()
+	}
+
+	if snapshot.backupPath != "" {
+		if err := os.Rename(snapshot.backupPath, writer.filePath); err != nil {
+			return errors.Wrap(err, "error restoring previous database from rollback snapshot")
+		}
+	}
+	return writer.fsyncDir()
+}
+
+// cleanupRollbackSnapshot removes the backup taken by snapshotForRollback
+// once Commit no longer needs it, i.e. validation succeeded.
+func (writer *LocalFileDatabaseWriter) cleanupRollbackSnapshot(snapshot *commitSnapshot) error {
+	if snapshot.backupPath != "" {
+		if err := os.Remove(snapshot.backupPath); err != nil && !os.IsNotExist(err) {
+			return errors.Wrap(err, "error removing rollback snapshot")
+		}
+	}
+	if snapshot.sidecarBackupPath != "" {
+		if err := os.Remove(snapshot.sidecarBackupPath); err != nil && !os.IsNotExist(err) {
+			return errors.Wrap(err, "error removing sidecar rollback snapshot")
+		}
+	}
+	return nil
+}
+
+func copyFileContents(src, dst string) error {
+	in, err := os.Open(src) //nolint:gosec
+	if err != nil {
+		return errors.Wrap(err, "error opening file to copy")
+	}
+	defer in.Close() //nolint:errcheck
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644) //nolint:gosec
+	if err != nil {
+		return errors.Wrap(err, "error creating copy destination")
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return errors.Wrap(err, "error copying file contents")
+	}
+	return out.Close()
+}
@@ -0,0 +1,184 @@
+package database
+
+import (
+	"bytes"
+	"crypto/md5" //nolint:gosec
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/pkg/errors"
+)
+
+// S3Backend is a Backend that stores databases as objects in an S3 bucket.
+// Uploads go to a ".tmp-<name>" key first and are only copied to the final
+// key once S3 has confirmed the upload matches the buffered data's MD5, so a reader never
+// observes a partially uploaded database.
+//
+// Commit uses a single PutObject call with a Content-MD5 header rather
+// than s3manager's multipart uploader: S3 rejects the request outright if
+// the bytes it received don't hash to that MD5, and — unlike a
+// multipart upload — the resulting object's ETag is the object's real
+// whole-file MD5, which Exists and callers of GetHash rely on. MMDB files
+// routinely exceed s3manager's default 5MB multipart threshold, so a
+// multipart upload's ETag (md5-of-part-MD5s, suffixed "-N") would never
+// match and Commit would never succeed.
+type S3Backend struct {
+	bucket string
+	prefix string
+	client *s3.S3
+}
+
+// NewS3Backend returns an S3Backend that stores objects under prefix in
+// bucket, using sess for uploads, copies, and deletes.
+func NewS3Backend(sess *session.Session, bucket, prefix string) *S3Backend {
+	return &S3Backend{
+		bucket: bucket,
+		prefix: prefix,
+		client: s3.New(sess),
+	}
+}
+
+func (b *S3Backend) key(name string) string {
+	return strings.TrimSuffix(b.prefix, "/") + "/" + name
+}
+
+func (b *S3Backend) tempKey(name string) string {
+	return b.key(".tmp-" + name)
+}
+
+// NewWriter returns a Writer that buffers the database locally; the upload
+// happens in Commit (see its comment), not here or on Close.
+func (b *S3Backend) NewWriter(name string, lastModified *time.Time) (Writer, error) {
+	if lastModified != nil {
+		name = fmt.Sprintf("%s-%s", name, lastModified.UTC().Format(timestampFormat))
+	}
+
+	oldHash := ZeroMD5
+	if exists, hash, err := b.Exists(name); err != nil {
+		return nil, err
+	} else if exists {
+		oldHash = hash
+	}
+
+	return &s3Writer{
+		backend: b,
+		name:    name,
+		oldHash: oldHash,
+		buf:     &bytes.Buffer{},
+		md5Hash: md5.New(), //nolint:gosec
+	}, nil
+}
+
+// Exists reports whether name is already present in the bucket and, if so,
+// returns the MD5 recorded in its ETag (valid for objects that were not
+// uploaded as multipart, which is true of everything this backend writes,
+// since Commit never uses a multipart upload).
+func (b *S3Backend) Exists(name string) (bool, string, error) {
+	out, err := b.client.HeadObject(&s3.HeadObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(b.key(name)),
+	})
+	if err != nil {
+		if isS3NotFound(err) {
+			return false, "", nil
+		}
+		return false, "", errors.Wrap(err, "error checking whether database exists in S3")
+	}
+	return true, strings.Trim(aws.StringValue(out.ETag), `"`), nil
+}
+
+// Commit uploads the buffered database to the temporary key in a single
+// PutObject call, with a Content-MD5 header S3 verifies server-side, then
+// copies it onto the final key and removes the temporary object.
+func (b *S3Backend) Commit(w Writer) error {
+	sw, ok := w.(*s3Writer)
+	if !ok {
+		return errors.Errorf("S3Backend.Commit called with a Writer of type %T", w)
+	}
+	if sw.backend != b {
+		return errors.Errorf("S3Backend.Commit called with a Writer from a different S3Backend")
+	}
+
+	tempKey := b.tempKey(sw.name)
+	contentMD5 := base64.StdEncoding.EncodeToString(sw.md5Hash.Sum(nil))
+
+	if _, err := b.client.PutObject(&s3.PutObjectInput{
+		Bucket:     aws.String(b.bucket),
+		Key:        aws.String(tempKey),
+		Body:       bytes.NewReader(sw.buf.Bytes()),
+		ContentMD5: aws.String(contentMD5),
+	}); err != nil {
+		return errors.Wrap(err, "error uploading database to S3")
+	}
+
+	if _, err := b.client.CopyObject(&s3.CopyObjectInput{
+		Bucket:     aws.String(b.bucket),
+		CopySource: aws.String(b.bucket + "/" + tempKey),
+		Key:        aws.String(b.key(sw.name)),
+	}); err != nil {
+		return errors.Wrap(err, "error promoting uploaded database to its final S3 key")
+	}
+
+	if _, err := b.client.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(tempKey),
+	}); err != nil {
+		return errors.Wrap(err, "error removing temporary S3 object")
+	}
+	return nil
+}
+
+func isS3NotFound(err error) bool {
+	if aerr, ok := err.(awserr.Error); ok {
+		return aerr.Code() == s3.ErrCodeNoSuchKey || aerr.Code() == "NotFound"
+	}
+	return false
+}
+
+type s3Writer struct {
+	backend      *S3Backend
+	name         string
+	oldHash      string
+	buf          *bytes.Buffer
+	md5Hash      hash.Hash
+	lastModified time.Time
+}
+
+func (w *s3Writer) Write(p []byte) (int, error) {
+	w.md5Hash.Write(p) //nolint:errcheck
+	return w.buf.Write(p)
+}
+
+func (w *s3Writer) Close() error {
+	return nil
+}
+
+func (w *s3Writer) ValidHash(expectedHash string) error {
+	actualHash := fmt.Sprintf("%x", w.md5Hash.Sum(nil))
+	if !strings.EqualFold(actualHash, expectedHash) {
+		return errors.Errorf("md5 of new database (%s) does not match expected md5 (%s)", actualHash, expectedHash)
+	}
+	return nil
+}
+
+func (w *s3Writer) UpdateFilepath(lastModified *time.Time) {
+	if lastModified != nil {
+		w.name = fmt.Sprintf("%s-%s", w.name, lastModified.UTC().Format(timestampFormat))
+	}
+}
+
+func (w *s3Writer) SetFileModificationTime(lastModified time.Time) error {
+	w.lastModified = lastModified
+	return nil
+}
+
+func (w *s3Writer) GetHash() string {
+	return w.oldHash
+}
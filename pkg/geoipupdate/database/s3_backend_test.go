@@ -0,0 +1,94 @@
+package database
+
+import (
+	"bytes"
+	"crypto/md5" //nolint:gosec
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/johannesboyne/gofakes3"
+	"github.com/johannesboyne/gofakes3/backend/s3mem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestS3Backend spins up an in-process fake S3 server (gofakes3) so the
+// upload-to-temp-key-then-copy path can be exercised without real AWS
+// credentials or network access.
+func newTestS3Backend(t *testing.T) (*S3Backend, func()) {
+	t.Helper()
+
+	faker := gofakes3.New(s3mem.New())
+	server := httptest.NewServer(faker.Server())
+
+	const bucket = "geoip-test"
+	sess, err := session.NewSession(&aws.Config{
+		Credentials:      credentials.NewStaticCredentials("dummy", "dummy", ""),
+		Endpoint:         aws.String(server.URL),
+		Region:           aws.String("us-east-1"),
+		S3ForcePathStyle: aws.Bool(true),
+	})
+	require.NoError(t, err)
+
+	backend := NewS3Backend(sess, bucket, "databases")
+	_, err = backend.client.CreateBucket(&s3.CreateBucketInput{Bucket: aws.String(bucket)})
+	require.NoError(t, err)
+
+	return backend, server.Close
+}
+
+func TestS3BackendWriteAndCommit(t *testing.T) {
+	backend, cleanup := newTestS3Backend(t)
+	defer cleanup()
+
+	exists, _, err := backend.Exists("GeoIP2-City.mmdb")
+	require.NoError(t, err)
+	require.False(t, exists)
+
+	writer, err := backend.NewWriter("GeoIP2-City.mmdb", nil)
+	require.NoError(t, err)
+
+	_, err = writer.Write([]byte("a fake mmdb"))
+	require.NoError(t, err)
+	require.NoError(t, backend.Commit(writer))
+
+	exists, hash, err := backend.Exists("GeoIP2-City.mmdb")
+	require.NoError(t, err)
+	require.True(t, exists)
+	require.NotEmpty(t, hash)
+
+	// The temporary upload key should not be left behind.
+	tempExists, _, err := backend.Exists(".tmp-GeoIP2-City.mmdb")
+	require.NoError(t, err)
+	require.False(t, tempExists)
+}
+
+// TestS3BackendCommitLargePayloadMatchesWholeObjectMD5 uses a payload well
+// past s3manager's default 5MB multipart threshold. A multipart upload's
+// ETag is md5(concat(part MD5s))-N, not the whole-object MD5, so if
+// Commit ever regresses to using the multipart uploader this test's
+// hash comparison will fail even though the upload itself succeeds.
+func TestS3BackendCommitLargePayloadMatchesWholeObjectMD5(t *testing.T) {
+	backend, cleanup := newTestS3Backend(t)
+	defer cleanup()
+
+	const size = 8 * 1024 * 1024 // bigger than the 5MB multipart default
+	payload := bytes.Repeat([]byte("geoipupdate-mmdb-filler-"), size/24+1)[:size]
+	expectedMD5 := fmt.Sprintf("%x", md5.Sum(payload)) //nolint:gosec
+
+	writer, err := backend.NewWriter("GeoIP2-City.mmdb", nil)
+	require.NoError(t, err)
+	_, err = writer.Write(payload)
+	require.NoError(t, err)
+	require.NoError(t, backend.Commit(writer))
+
+	exists, hash, err := backend.Exists("GeoIP2-City.mmdb")
+	require.NoError(t, err)
+	require.True(t, exists)
+	assert.Equal(t, expectedMD5, hash, "a committed large database's ETag must be its true whole-object MD5")
+}
@@ -0,0 +1,22 @@
+package database
+
+// Validator checks that a freshly committed database file is actually
+// usable before LocalFileDatabaseWriter.Commit reports success. This
+// catches the class of failure where a download is MD5-valid (it matches
+// the hash MaxMind's API advertised) but the MMDB file itself is
+// semantically broken, e.g. truncated mid-tree.
+type Validator interface {
+	// Validate is called with the path of the just-committed database and
+	// the edition ID (e.g. "GeoIP2-City") that was requested for it.
+	Validate(path string, editionID string) error
+}
+
+// NoopValidator performs no validation. It is the default Validator that
+// NewLocalFileDatabaseWriter installs, and is useful in tests that don't want to
+// depend on maxminddb-golang or ship a real MMDB fixture.
+type NoopValidator struct{}
+
+// Validate always returns nil.
+func (NoopValidator) Validate(string, string) error {
+	return nil
+}
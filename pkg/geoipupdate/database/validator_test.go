@@ -0,0 +1,160 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type failingValidator struct{}
+
+func (failingValidator) Validate(string, string) error {
+	return errors.New("simulated validation failure")
+}
+
+func TestCommitRollsBackOnValidationFailureWithExistingDatabase(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "GeoIP2-City.mmdb")
+	lockPath := filepath.Join(dir, "GeoIP2-City.mmdb.lock")
+	require.NoError(t, os.WriteFile(dbPath, []byte("old good database"), 0o644))
+
+	writer, err := NewLocalFileDatabaseWriter(dbPath, lockPath, false, WithValidator(failingValidator{}, "GeoIP2-City"))
+	require.NoError(t, err)
+	_, err = writer.Write([]byte("new corrupt database"))
+	require.NoError(t, err)
+
+	err = writer.Commit()
+	require.Error(t, err)
+
+	contents, readErr := os.ReadFile(dbPath)
+	require.NoError(t, readErr)
+	assert.Equal(t, "old good database", string(contents), "the previous database must be restored on validation failure")
+
+	assert.NoFileExists(t, dbPath+".rollback")
+}
+
+func TestCommitRollsBackOnValidationFailureWithNoPriorDatabase(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "GeoIP2-City.mmdb")
+	lockPath := filepath.Join(dir, "GeoIP2-City.mmdb.lock")
+
+	writer, err := NewLocalFileDatabaseWriter(dbPath, lockPath, false, WithValidator(failingValidator{}, "GeoIP2-City"))
+	require.NoError(t, err)
+	_, err = writer.Write([]byte("new corrupt database"))
+	require.NoError(t, err)
+
+	err = writer.Commit()
+	require.Error(t, err)
+
+	assert.NoFileExists(t, dbPath, "a database that never validates should not be left behind")
+}
+
+// TestCommitDoesNotPruneBeforeValidationSucceeds is a regression test: with
+// RetainVersions=1, pruning a new (but not-yet-validated) snapshot's
+// predecessor before validation ran would delete the one good database
+// rollback needs to restore, leaving a dangling symlink and no working
+// database at all.
+func TestCommitDoesNotPruneBeforeValidationSucceeds(t *testing.T) {
+	dir := t.TempDir()
+	symlinkPath := filepath.Join(dir, "GeoIP2-City.mmdb")
+	lockPath := filepath.Join(dir, "GeoIP2-City.mmdb.lock")
+
+	oldTime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	oldSnapshotPath := symlinkPath + "-" + oldTime.UTC().Format(timestampFormat)
+	require.NoError(t, os.WriteFile(oldSnapshotPath, []byte("old good database"), 0o644))
+	require.NoError(t, os.Symlink(filepath.Base(oldSnapshotPath), symlinkPath))
+
+	writer, err := NewLocalFileDatabaseWriter(
+		symlinkPath, lockPath, false,
+		WithRetention(RetainModeCount, 1, 0),
+		WithValidator(failingValidator{}, "GeoIP2-City"),
+	)
+	require.NoError(t, err)
+
+	newTime := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	writer.UpdateFilepath(&newTime)
+
+	_, err = writer.Write([]byte("new corrupt database"))
+	require.NoError(t, err)
+
+	err = writer.Commit()
+	require.Error(t, err)
+
+	assert.FileExists(t, oldSnapshotPath, "pruning must not remove the previous snapshot before a failed validation can roll back to it")
+
+	target, err := os.Readlink(symlinkPath)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Base(oldSnapshotPath), target, "symlink must not be left dangling after a rolled-back commit")
+
+	contents, err := os.ReadFile(symlinkPath)
+	require.NoError(t, err)
+	assert.Equal(t, "old good database", string(contents))
+}
+
+// TestCommitRestoresSidecarOnValidationFailure covers WithHashAlgorithm
+// combined with a failing Validator: the database content is rolled back,
+// and its sidecar hash file must be rolled back with it, or a later
+// --verify-strong-hash check would wrongly refuse to serve the
+// (correctly restored) database.
+func TestCommitRestoresSidecarOnValidationFailure(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "GeoIP2-City.mmdb")
+
+	writer, err := NewLocalFileDatabaseWriter(
+		dbPath, filepath.Join(dir, "good.lock"), false,
+		WithHashAlgorithm(HashAlgorithmSHA256),
+	)
+	require.NoError(t, err)
+	_, err = writer.Write([]byte("old good database"))
+	require.NoError(t, err)
+	require.NoError(t, writer.Commit())
+
+	goodSidecar, err := os.ReadFile(dbPath + ".sha256")
+	require.NoError(t, err)
+
+	writer2, err := NewLocalFileDatabaseWriter(
+		dbPath, filepath.Join(dir, "bad.lock"), false,
+		WithHashAlgorithm(HashAlgorithmSHA256),
+		WithValidator(failingValidator{}, "GeoIP2-City"),
+	)
+	require.NoError(t, err)
+	_, err = writer2.Write([]byte("new corrupt database"))
+	require.NoError(t, err)
+
+	err = writer2.Commit()
+	require.Error(t, err)
+
+	contents, err := os.ReadFile(dbPath)
+	require.NoError(t, err)
+	assert.Equal(t, "old good database", string(contents))
+
+	sidecar, err := os.ReadFile(dbPath + ".sha256")
+	require.NoError(t, err)
+	assert.Equal(t, string(goodSidecar), string(sidecar), "the sidecar hash file must be restored along with the database")
+
+	ok, err := VerifyStrongHash(dbPath, HashAlgorithmSHA256)
+	require.NoError(t, err)
+	assert.True(t, ok, "--verify-strong-hash must accept the rolled-back database")
+}
+
+func TestCommitSucceedsWithPassingValidator(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "GeoIP2-City.mmdb")
+	lockPath := filepath.Join(dir, "GeoIP2-City.mmdb.lock")
+
+	writer, err := NewLocalFileDatabaseWriter(dbPath, lockPath, false, WithValidator(NoopValidator{}, "GeoIP2-City"))
+	require.NoError(t, err)
+	_, err = writer.Write([]byte("a fake mmdb"))
+	require.NoError(t, err)
+	require.NoError(t, writer.Commit())
+
+	contents, err := os.ReadFile(dbPath)
+	require.NoError(t, err)
+	assert.Equal(t, "a fake mmdb", string(contents))
+	assert.NoFileExists(t, dbPath+".rollback")
+}
@@ -0,0 +1,34 @@
+package database
+
+import (
+	"io"
+	"time"
+)
+
+// Writer is the interface implemented by anything that can receive a
+// downloaded database, validate its hash, and commit it to a backend.
+// LocalFileDatabaseWriter is the original implementation; backends such as
+// the in-memory, S3, and GCS ones in this package return their own Writer
+// implementations from Backend.NewWriter.
+type Writer interface {
+	io.Writer
+
+	// Close releases any resources (locks, temporary files) held by the
+	// Writer without committing its contents.
+	Close() error
+
+	// ValidHash checks that the data written so far hashes to expectedHash.
+	ValidHash(expectedHash string) error
+
+	// UpdateFilepath lets the Writer fold the database's last-modified time
+	// into its eventual name, e.g. for timestamped snapshots.
+	UpdateFilepath(lastModified *time.Time)
+
+	// SetFileModificationTime records the server-provided last-modified
+	// time against the committed database.
+	SetFileModificationTime(lastModified time.Time) error
+
+	// GetHash returns the hash of the database that was present before this
+	// Writer was created, or ZeroMD5 if there was none.
+	GetHash() string
+}